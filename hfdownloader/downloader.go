@@ -1,16 +1,26 @@
 package hfdownloader
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,17 +30,82 @@ const (
 	DOWNLOAD_CHUNK_SIZE = 8192
 )
 
+// ErrNotFound is returned (via errors.Is) when the Hugging Face API or a
+// file host responds with 404.
+var ErrNotFound = errors.New("resource not found")
+
+// ErrUnauthorized is returned (via errors.Is) when a request is rejected
+// with 401 or 403, typically meaning HF_TOKEN is missing or lacks access.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrRateLimited is returned (via errors.Is) when a request is rejected
+// with 429 after exhausting the retry policy.
+var ErrRateLimited = errors.New("rate limited")
+
+// RepoType identifies the kind of repository being downloaded, matching the
+// three kinds Hugging Face hosts under huggingface.co.
+type RepoType string
+
+const (
+	RepoTypeModel   RepoType = "model"
+	RepoTypeDataset RepoType = "dataset"
+	RepoTypeSpace   RepoType = "space"
+)
+
+// apiPath returns the plural path segment the tree-listing API uses for
+// this repo type, e.g. "datasets" in "/api/datasets/<id>/tree/<rev>".
+func (t RepoType) apiPath() string {
+	switch t {
+	case RepoTypeDataset:
+		return "datasets"
+	case RepoTypeSpace:
+		return "spaces"
+	default:
+		return "models"
+	}
+}
+
+// urlPrefix returns the path segment Hugging Face's file URLs use for this
+// repo type. Model URLs are rootless (huggingface.co/<id>/resolve/...);
+// datasets and spaces are nested under their plural name.
+func (t RepoType) urlPrefix() string {
+	switch t {
+	case RepoTypeDataset:
+		return "datasets/"
+	case RepoTypeSpace:
+		return "spaces/"
+	default:
+		return ""
+	}
+}
+
 // Downloader handles downloading models from Hugging Face
 type Downloader struct {
-	customPath     string
-	ignorePatterns []string
-	client         *http.Client
+	customPath            string
+	ignorePatterns        []string
+	allowPatterns         []string
+	client                *http.Client
+	maxConnectionsPerFile int
+	maxConcurrentFiles    int
+	bandwidthLimiter      *bandwidthLimiter
+	reporter              ProgressReporter
+	logger                *slog.Logger
+	retryMaxAttempts      int
+	retryInitialBackoff   time.Duration
+	retryMaxBackoff       time.Duration
 }
 
 // NewDownloader creates a new instance of Downloader with default settings
 func NewDownloader() *Downloader {
 	return &Downloader{
-		ignorePatterns: []string{`\.md$`, `\.txt$`},
+		ignorePatterns:        []string{`\.md$`, `\.txt$`},
+		maxConnectionsPerFile: 1,
+		maxConcurrentFiles:    runtime.NumCPU() * 2,
+		reporter:              &SilentProgressReporter{},
+		logger:                slog.Default(),
+		retryMaxAttempts:      3,
+		retryInitialBackoff:   500 * time.Millisecond,
+		retryMaxBackoff:       10 * time.Second,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -60,22 +135,113 @@ func (d *Downloader) SetIgnorePatterns(patterns []string) {
 	d.ignorePatterns = patterns
 }
 
-// Download downloads a model from Hugging Face
-func (d *Downloader) Download(modelRepo, revision string) (string, error) {
+// SetAllowPatterns restricts a download to siblings whose path matches at
+// least one of the given regular expressions, the inverse of
+// SetIgnorePatterns. This is useful for repos with hundreds of files (e.g.
+// dataset shards) when a caller only wants a subset, such as "just the
+// tokenizer + config" or "just the *.safetensors shards". An empty or nil
+// slice (the default) allows everything not otherwise ignored.
+func (d *Downloader) SetAllowPatterns(patterns []string) {
+	d.allowPatterns = patterns
+}
+
+// SetMaxConnectionsPerFile sets how many byte-range shards a single large
+// file is split into and downloaded concurrently. A value of 1 (the
+// default) disables sharding and downloads each file over a single
+// connection. Sharding only takes effect when the server advertises
+// `Accept-Ranges: bytes` for the file being downloaded; otherwise the
+// single-stream path is used regardless of this setting.
+func (d *Downloader) SetMaxConnectionsPerFile(n int) {
+	if n < 1 {
+		n = 1
+	}
+	d.maxConnectionsPerFile = n
+}
+
+// SetMaxConcurrentFiles sets how many files are downloaded at once. Download
+// feeds the repo's siblings to a fixed-size pool of this many workers instead
+// of spawning one goroutine per file, so repos with thousands of siblings
+// don't exhaust file descriptors or saturate the network unpredictably. The
+// default is runtime.NumCPU()*2.
+func (d *Downloader) SetMaxConcurrentFiles(n int) {
+	if n < 1 {
+		n = 1
+	}
+	d.maxConcurrentFiles = n
+}
+
+// SetBandwidthLimit caps total download throughput across all files and
+// shards to bytesPerSecond. A value <= 0 removes the cap (the default).
+func (d *Downloader) SetBandwidthLimit(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		d.bandwidthLimiter = nil
+		return
+	}
+	d.bandwidthLimiter = newBandwidthLimiter(bytesPerSecond)
+}
+
+// SetProgressReporter sets the ProgressReporter used to surface download
+// progress. The default is a SilentProgressReporter so library use doesn't
+// write to stdout unless a caller opts in; pass a TerminalProgressReporter
+// (or your own implementation) to render progress instead.
+func (d *Downloader) SetProgressReporter(reporter ProgressReporter) {
+	if reporter == nil {
+		reporter = &SilentProgressReporter{}
+	}
+	d.reporter = reporter
+}
+
+// SetLogger sets the structured logger used for warnings and diagnostics.
+// The default logs to slog.Default(); pass slog.New(slog.NewTextHandler(io.Discard, nil))
+// to silence it.
+func (d *Downloader) SetLogger(logger *slog.Logger) {
+	d.logger = logger
+}
+
+// SetRetryPolicy configures how HTTP requests are retried. Requests are
+// retried on network errors, 5xx responses other than 501 Not Implemented,
+// and 429 responses (honoring a Retry-After header when present), using
+// jittered exponential backoff
+// starting at initialBackoff and capped at maxBackoff. maxAttempts counts
+// the first attempt, so 1 disables retries.
+func (d *Downloader) SetRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	d.retryMaxAttempts = maxAttempts
+	d.retryInitialBackoff = initialBackoff
+	d.retryMaxBackoff = maxBackoff
+}
+
+// Download downloads a model from Hugging Face. The supplied context can be
+// used to cancel the download, including any in-flight parallel file
+// transfers. It is a thin wrapper around DownloadRepo for RepoTypeModel.
+func (d *Downloader) Download(ctx context.Context, modelRepo, revision string) (string, error) {
+	return d.DownloadRepo(ctx, RepoTypeModel, modelRepo, revision)
+}
+
+// DownloadRepo downloads a model, dataset, or Space repository from Hugging
+// Face. The supplied context can be used to cancel the download, including
+// any in-flight parallel file transfers.
+func (d *Downloader) DownloadRepo(ctx context.Context, repoType RepoType, repoID, revision string) (string, error) {
 	if revision == "" {
 		revision = "main"
 	}
 
-	// Compile ignore patterns
+	// Compile ignore and allow patterns
 	ignoreRegexps := make([]*regexp.Regexp, len(d.ignorePatterns))
 	for i, pattern := range d.ignorePatterns {
 		ignoreRegexps[i] = regexp.MustCompile(pattern)
 	}
+	allowRegexps := make([]*regexp.Regexp, len(d.allowPatterns))
+	for i, pattern := range d.allowPatterns {
+		allowRegexps[i] = regexp.MustCompile(pattern)
+	}
 
-	// Get model information
-	modelInfo, commitHash, err := d.getModelInfo(modelRepo, revision)
+	// Get repo information
+	modelInfo, commitHash, err := d.getModelInfo(ctx, repoType, repoID, revision)
 	if err != nil {
-		return "", fmt.Errorf("could not get model info: %w", err)
+		return "", fmt.Errorf("could not get repo info: %w", err)
 	}
 
 	// Determine storage location
@@ -87,7 +253,7 @@ func (d *Downloader) Download(modelRepo, revision string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("could not get home directory: %w", err)
 		}
-		repoPath := d.repoFolderName(modelRepo, "model")
+		repoPath := d.repoFolderName(repoID, string(repoType))
 		storageFolder = filepath.Join(homeDir, ".cache", "huggingface", "hub", repoPath)
 	}
 
@@ -108,7 +274,7 @@ func (d *Downloader) Download(modelRepo, revision string) (string, error) {
 		refPath := filepath.Join(storageFolder, "refs", revision)
 		os.MkdirAll(filepath.Dir(refPath), 0755)
 		if err := os.WriteFile(refPath, []byte(commitHash), 0644); err != nil {
-			fmt.Printf("Warning: Could not write revision reference: %v\n", err)
+			d.logger.Warn("could not write revision reference", "error", err)
 		}
 	}
 
@@ -118,41 +284,81 @@ func (d *Downloader) Download(modelRepo, revision string) (string, error) {
 		return "", fmt.Errorf("could not create snapshot directory: %w", err)
 	}
 
-	// Download files in parallel
-	var wg sync.WaitGroup
-	errorChan := make(chan error, len(modelInfo.Siblings))
-
+	// Filter siblings up front so the overall progress total reflects only
+	// what will actually be downloaded.
+	toDownload := make([]ModelSibling, 0, len(modelInfo.Siblings))
+	var totalBytes int64
 	for _, file := range modelInfo.Siblings {
-		shouldDownload := true
+		ignored := false
 		for _, pattern := range ignoreRegexps {
 			if pattern.MatchString(file.RID) {
-				shouldDownload = false
+				ignored = true
 				break
 			}
 		}
-
-		if shouldDownload {
-			wg.Add(1)
-			go func(filename string, blobId string, size int64) {
-				defer wg.Done()
-				if err := d.downloadFile(modelRepo, revision, filename, blobId, size, storageFolder, snapshotDir); err != nil {
-					errorChan <- fmt.Errorf("error processing %s: %w", filename, err)
+		if !ignored && len(allowRegexps) > 0 {
+			ignored = true
+			for _, pattern := range allowRegexps {
+				if pattern.MatchString(file.RID) {
+					ignored = false
+					break
 				}
-			}(file.RID, file.Blob, file.Size)
+			}
+		}
+		if !ignored {
+			toDownload = append(toDownload, file)
+			totalBytes += file.Size
 		}
 	}
 
+	overall := &overallProgress{reporter: d.reporter, bytesTotal: totalBytes, filesTotal: len(toDownload)}
+	overall.report()
+
+	// Download files through a bounded pool of workers, rather than spawning
+	// one goroutine per file, so repos with thousands of siblings don't
+	// exhaust file descriptors or saturate the network unpredictably.
+	jobs := make(chan ModelSibling, len(toDownload))
+	for _, file := range toDownload {
+		jobs <- file
+	}
+	close(jobs)
+
+	numWorkers := d.maxConcurrentFiles
+	if numWorkers > len(toDownload) {
+		numWorkers = len(toDownload)
+	}
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				if err := d.downloadFile(ctx, repoType, repoID, revision, file.RID, file.Blob, file.Size, storageFolder, snapshotDir, file.LFS, overall); err != nil {
+					errsMu.Lock()
+					errs = append(errs, fmt.Errorf("error processing %s: %w", file.RID, err))
+					errsMu.Unlock()
+				}
+			}
+		}()
+	}
+
 	wg.Wait()
-	close(errorChan)
 
-	for err := range errorChan {
+	if err := errors.Join(errs...); err != nil {
 		return "", err
 	}
 
 	return storageFolder, nil
 }
 
-func (d *Downloader) downloadFile(modelRepo, revision, filename, blobId string, size int64, storageFolder, snapshotDir string) error {
+func (d *Downloader) downloadFile(ctx context.Context, repoType RepoType, repoID, revision, filename, blobId string, size int64, storageFolder, snapshotDir string, lfs *LfsPointer, overall *overallProgress) error {
 	relativePath := strings.ReplaceAll(filename, "/", string(os.PathSeparator))
 	blobPath := filepath.Join(storageFolder, "blobs", blobId)
 	pointerPath := filepath.Join(snapshotDir, relativePath)
@@ -167,26 +373,50 @@ func (d *Downloader) downloadFile(modelRepo, revision, filename, blobId string,
 	}
 
 	if _, err := os.Stat(pointerPath); err == nil && !blobExists {
-		fmt.Printf("Warning: Pointer exists but blob missing for %s, redownloading\n", filename)
+		d.logger.Warn("pointer exists but blob missing, redownloading", "file", filename)
 	} else if err == nil && blobExists {
-		fmt.Printf("File already exists: %s\n", filename)
+		d.reporter.FileCompleted(filename)
+		overall.addBytes(size)
+		overall.completeFile()
 		return nil
 	}
 
 	if !blobExists {
-		url := fmt.Sprintf("https://huggingface.co/%s/resolve/%s/%s", modelRepo, revision, filename)
-		metadata, err := d.getFileMetadata(url)
+		downloadURL := fmt.Sprintf("https://huggingface.co/%s%s/resolve/%s/%s", repoType.urlPrefix(), repoID, revision, filename)
+		var extraHeaders map[string]string
+
+		if lfs != nil {
+			href, headers, err := d.getLFSObjectURL(ctx, repoType, repoID, lfs.Oid, lfs.Size)
+			switch {
+			case err == nil:
+				downloadURL = href
+				extraHeaders = headers
+			case errors.Is(err, errLFSBatchUnavailable):
+				// Server doesn't support the batch API; fall back to resolve/.
+			default:
+				return fmt.Errorf("error getting LFS download info: %w", err)
+			}
+		}
+
+		metadata, err := d.getFileMetadata(ctx, downloadURL, extraHeaders)
 		if err != nil {
 			return fmt.Errorf("error getting metadata: %w", err)
 		}
 
-		fmt.Printf("Downloading: %s (%.2f MB)\n", filename, float64(metadata.Size)/1024/1024)
+		d.reporter.FileStarted(filename, metadata.Size)
 
 		tempPath := blobPath + ".incomplete"
-		if err := d.downloadWithProgress(url, tempPath, metadata.Size); err != nil {
+		if err := d.downloadWithProgress(ctx, filename, downloadURL, tempPath, metadata.Size, metadata.AcceptRanges, extraHeaders, overall); err != nil {
 			return fmt.Errorf("error downloading: %w", err)
 		}
 
+		if lfs != nil {
+			if err := verifySHA256(tempPath, lfs.Oid); err != nil {
+				os.Remove(tempPath)
+				return fmt.Errorf("LFS integrity check failed for %s: %w", filename, err)
+			}
+		}
+
 		if err := os.Rename(tempPath, blobPath); err != nil {
 			return fmt.Errorf("error renaming temp file: %w", err)
 		}
@@ -203,7 +433,7 @@ func (d *Downloader) downloadFile(modelRepo, revision, filename, blobId string,
 		}
 
 		if err := os.Symlink(relPath, pointerPath); err != nil {
-			fmt.Printf("Warning: Could not create symlink, copying file instead: %v\n", err)
+			d.logger.Warn("could not create symlink, copying file instead", "error", err)
 			if err := copyFile(blobPath, pointerPath); err != nil {
 				return fmt.Errorf("error copying: %w", err)
 			}
@@ -214,26 +444,28 @@ func (d *Downloader) downloadFile(modelRepo, revision, filename, blobId string,
 		}
 	}
 
-	fmt.Printf("Processed: %s\n", filename)
+	d.reporter.FileCompleted(filename)
+	overall.completeFile()
 	return nil
 }
 
 // Helper functions...
 
-func (d *Downloader) getModelInfo(modelRepo, revision string) (*ModelInfo, string, error) {
-	url := fmt.Sprintf("%s/models/%s/tree/%s", HF_API_URL, modelRepo, revision)
+func (d *Downloader) getModelInfo(ctx context.Context, repoType RepoType, repoID, revision string) (*ModelInfo, string, error) {
+	url := fmt.Sprintf("%s/%s/%s/tree/%s", HF_API_URL, repoType.apiPath(), repoID, revision)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, "", fmt.Errorf("error creating request: %w", err)
-	}
-
-	if token := os.Getenv(HF_TOKEN_ENV); token != "" {
-		req.Header.Add("Authorization", "Bearer "+token)
-	}
-	req.Header.Add("User-Agent", "huggingface-go/0.1")
+	resp, err := d.doWithRetry(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
 
-	resp, err := d.client.Do(req)
+		if token := os.Getenv(HF_TOKEN_ENV); token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		req.Header.Add("User-Agent", "huggingface-go/0.1")
+		return req, nil
+	})
 	if err != nil {
 		return nil, "", fmt.Errorf("error making API request: %w", err)
 	}
@@ -241,15 +473,18 @@ func (d *Downloader) getModelInfo(modelRepo, revision string) (*ModelInfo, strin
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
+		if sentinel := classifyStatusError(resp); sentinel != nil {
+			return nil, "", fmt.Errorf("API request failed: %w", sentinel)
+		}
 		return nil, "", fmt.Errorf("API returned status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var files []struct {
-		Type    string    `json:"type"`
-		Path    string    `json:"path"`
-		Oid     string    `json:"oid"`
-		Size    int64     `json:"size"`
-		LfsInfo *struct{} `json:"lfs"`
+		Type string      `json:"type"`
+		Path string      `json:"path"`
+		Oid  string      `json:"oid"`
+		Size int64       `json:"size"`
+		LFS  *LfsPointer `json:"lfs"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
@@ -257,26 +492,16 @@ func (d *Downloader) getModelInfo(modelRepo, revision string) (*ModelInfo, strin
 	}
 
 	modelInfo := &ModelInfo{
-		Siblings: make([]struct {
-			RID  string    `json:"rfilename"`
-			Size int64     `json:"size"`
-			Blob string    `json:"blob_id"`
-			LFS  *struct{} `json:"lfs,omitempty"`
-		}, 0),
+		Siblings: make([]ModelSibling, 0),
 	}
 
 	for _, file := range files {
 		if file.Type == "file" {
-			modelInfo.Siblings = append(modelInfo.Siblings, struct {
-				RID  string    `json:"rfilename"`
-				Size int64     `json:"size"`
-				Blob string    `json:"blob_id"`
-				LFS  *struct{} `json:"lfs,omitempty"`
-			}{
+			modelInfo.Siblings = append(modelInfo.Siblings, ModelSibling{
 				RID:  file.Path,
 				Size: file.Size,
 				Blob: file.Oid,
-				LFS:  file.LfsInfo,
+				LFS:  file.LFS,
 			})
 		}
 	}
@@ -292,32 +517,38 @@ func (d *Downloader) getModelInfo(modelRepo, revision string) (*ModelInfo, strin
 	return modelInfo, commitHash, nil
 }
 
-func (d *Downloader) getFileMetadata(url string) (*HfFileMetadata, error) {
-	req, err := http.NewRequest("HEAD", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating HEAD request: %w", err)
-	}
-
-	if token := os.Getenv(HF_TOKEN_ENV); token != "" {
-		req.Header.Add("Authorization", "Bearer "+token)
-	}
-	req.Header.Add("Accept-Encoding", "identity")
+func (d *Downloader) getFileMetadata(ctx context.Context, url string, extraHeaders map[string]string) (*HfFileMetadata, error) {
+	resp, err := d.doWithRetry(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating HEAD request: %w", err)
+		}
 
-	resp, err := d.client.Do(req)
+		if token := os.Getenv(HF_TOKEN_ENV); token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		req.Header.Add("Accept-Encoding", "identity")
+		applyExtraHeaders(req, extraHeaders)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error making HEAD request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		if sentinel := classifyStatusError(resp); sentinel != nil {
+			return nil, fmt.Errorf("HEAD request failed: %w", sentinel)
+		}
 		return nil, fmt.Errorf("HEAD request returned status code: %d", resp.StatusCode)
 	}
 
 	metadata := &HfFileMetadata{
-		CommitHash: resp.Header.Get("X-Repo-Commit"),
-		Etag:       normalizeETag(resp.Header.Get("X-Linked-Etag")),
-		Location:   resp.Request.URL.String(),
-		Size:       parseInt64(resp.Header.Get("Content-Length")),
+		CommitHash:   resp.Header.Get("X-Repo-Commit"),
+		Etag:         normalizeETag(resp.Header.Get("X-Linked-Etag")),
+		Location:     resp.Request.URL.String(),
+		Size:         parseInt64(resp.Header.Get("Content-Length")),
+		AcceptRanges: resp.Header.Get("Accept-Ranges") == "bytes",
 	}
 
 	if metadata.Etag == "" {
@@ -327,43 +558,93 @@ func (d *Downloader) getFileMetadata(url string) (*HfFileMetadata, error) {
 	return metadata, nil
 }
 
-func (d *Downloader) downloadWithProgress(url, filepath string, expectedSize int64) error {
+// downloadWithProgress downloads url into filepath, resuming a previously
+// interrupted ".incomplete" file when the server supports range requests.
+// When acceptRanges is true, maxConnectionsPerFile > 1, and expectedSize is
+// known (greater than zero), the download is sharded across up to N
+// concurrent range requests (never more shards than bytes); otherwise, or
+// if the sharded download fails, it uses the single-stream path.
+func (d *Downloader) downloadWithProgress(ctx context.Context, filename, url, filepath string, expectedSize int64, acceptRanges bool, extraHeaders map[string]string, overall *overallProgress) error {
 	if err := os.MkdirAll(path.Dir(filepath), 0755); err != nil {
 		return fmt.Errorf("could not create directory: %w", err)
 	}
 
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("could not create file: %w", err)
+	if acceptRanges && expectedSize > 0 && d.maxConnectionsPerFile > 1 {
+		if err := d.downloadSharded(ctx, filename, url, filepath, expectedSize, extraHeaders, overall); err != nil {
+			d.logger.Warn("sharded download failed, falling back to single stream", "error", err)
+		} else {
+			return nil
+		}
 	}
-	defer out.Close()
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("error creating request: %w", err)
+	return d.downloadSingleStream(ctx, filename, url, filepath, expectedSize, acceptRanges, extraHeaders, overall)
+}
+
+// downloadSingleStream downloads url into filepath over one connection,
+// resuming from the end of a partially-downloaded file when the server
+// advertises range support.
+func (d *Downloader) downloadSingleStream(ctx context.Context, filename, url, filepath string, expectedSize int64, acceptRanges bool, extraHeaders map[string]string, overall *overallProgress) error {
+	var startOffset int64
+	flags := os.O_CREATE | os.O_WRONLY
+	if fi, err := os.Stat(filepath); err == nil && acceptRanges && fi.Size() > 0 && (expectedSize <= 0 || fi.Size() < expectedSize) {
+		startOffset = fi.Size()
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
 	}
 
-	if token := os.Getenv(HF_TOKEN_ENV); token != "" {
-		req.Header.Add("Authorization", "Bearer "+token)
+	out, err := os.OpenFile(filepath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
 	}
+	defer out.Close()
 
-	resp, err := d.client.Do(req)
+	resp, err := d.doWithRetry(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+
+		if token := os.Getenv(HF_TOKEN_ENV); token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		applyExtraHeaders(req, extraHeaders)
+		if startOffset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startOffset))
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if startOffset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// Server ignored our Range header; restart from scratch.
+		resp.Body.Close()
+		out.Close()
+		return d.downloadSingleStream(ctx, filename, url, filepath, expectedSize, false, extraHeaders, overall)
+	}
+	if startOffset == 0 && resp.StatusCode != http.StatusOK {
+		if sentinel := classifyStatusError(resp); sentinel != nil {
+			return fmt.Errorf("download request failed: %w", sentinel)
+		}
 		return fmt.Errorf("bad status: %s", resp.Status)
 	}
 
-	var downloaded int64
+	tp := newTransferProgress(filename, expectedSize, d.reporter, overall, startOffset)
+	downloaded := startOffset
 	lastProgressUpdate := time.Now()
 	progressInterval := 1 * time.Second
 
+	var body io.Reader = resp.Body
+	if d.bandwidthLimiter != nil {
+		body = d.bandwidthLimiter.wrap(body)
+	}
+
 	buffer := make([]byte, DOWNLOAD_CHUNK_SIZE)
 	for {
-		n, err := resp.Body.Read(buffer)
+		n, err := body.Read(buffer)
 		if err != nil && err != io.EOF {
 			return fmt.Errorf("error reading response: %w", err)
 		}
@@ -374,13 +655,9 @@ func (d *Downloader) downloadWithProgress(url, filepath string, expectedSize int
 			}
 
 			downloaded += int64(n)
+			tp.add(int64(n))
 			if time.Since(lastProgressUpdate) > progressInterval {
-				if expectedSize > 0 {
-					percentage := float64(downloaded) / float64(expectedSize) * 100
-					fmt.Printf("  %.1f%% (%d/%d bytes)\r", percentage, downloaded, expectedSize)
-				} else {
-					fmt.Printf("  %d bytes downloaded\r", downloaded)
-				}
+				tp.report()
 				lastProgressUpdate = time.Now()
 			}
 		}
@@ -390,7 +667,7 @@ func (d *Downloader) downloadWithProgress(url, filepath string, expectedSize int
 		}
 	}
 
-	fmt.Println()
+	tp.report()
 
 	if expectedSize > 0 && downloaded != expectedSize {
 		return fmt.Errorf("download size mismatch: got %d bytes, expected %d bytes", downloaded, expectedSize)
@@ -399,6 +676,266 @@ func (d *Downloader) downloadWithProgress(url, filepath string, expectedSize int
 	return nil
 }
 
+// overallProgress aggregates byte/file counts across every sibling in a
+// single Download call and reports them to the configured ProgressReporter.
+type overallProgress struct {
+	reporter   ProgressReporter
+	bytesTotal int64
+	filesTotal int
+	bytesDone  atomic.Int64
+	filesDone  atomic.Int64
+}
+
+func (p *overallProgress) addBytes(n int64) {
+	p.bytesDone.Add(n)
+}
+
+func (p *overallProgress) report() {
+	p.reporter.OverallProgress(p.bytesDone.Load(), p.bytesTotal, int(p.filesDone.Load()), p.filesTotal)
+}
+
+func (p *overallProgress) completeFile() {
+	p.filesDone.Add(1)
+	p.report()
+}
+
+// transferProgress tracks a single file's downloaded bytes (summed across
+// shards, when sharded) and forwards updates to both the per-file reporter
+// callback and the Download call's overallProgress.
+type transferProgress struct {
+	filename   string
+	size       int64
+	downloaded atomic.Int64
+	reporter   ProgressReporter
+	overall    *overallProgress
+}
+
+func newTransferProgress(filename string, size int64, reporter ProgressReporter, overall *overallProgress, resumeFrom int64) *transferProgress {
+	tp := &transferProgress{filename: filename, size: size, reporter: reporter, overall: overall}
+	tp.downloaded.Store(resumeFrom)
+	return tp
+}
+
+func (tp *transferProgress) add(n int64) {
+	tp.downloaded.Add(n)
+	if tp.overall != nil {
+		tp.overall.addBytes(n)
+	}
+}
+
+func (tp *transferProgress) report() {
+	tp.reporter.FileProgress(tp.filename, tp.downloaded.Load(), tp.size)
+	if tp.overall != nil {
+		tp.overall.report()
+	}
+}
+
+// fileShard describes one contiguous byte range of a sharded download and
+// how much of that range has been written so far.
+type fileShard struct {
+	Start      int64 `json:"start"`
+	End        int64 `json:"end"`
+	Downloaded int64 `json:"downloaded"`
+}
+
+// shardState is persisted alongside a ".incomplete" file (as
+// "<file>.incomplete.state") so an interrupted sharded download can pick
+// each shard back up instead of restarting the whole file.
+type shardState struct {
+	URL       string      `json:"url"`
+	Size      int64       `json:"size"`
+	NumShards int         `json:"num_shards"`
+	Shards    []fileShard `json:"shards"`
+}
+
+// downloadSharded splits url into maxConnectionsPerFile contiguous byte
+// ranges, downloads them concurrently, and writes each into its offset in
+// filepath via WriteAt. Progress is checkpointed to a sidecar state file so
+// a later retry can resume only the incomplete shards.
+func (d *Downloader) downloadSharded(ctx context.Context, filename, url, filepath string, size int64, extraHeaders map[string]string, overall *overallProgress) error {
+	numShards := d.maxConnectionsPerFile
+	if int64(numShards) > size {
+		numShards = int(size)
+	}
+	if numShards < 1 {
+		numShards = 1
+	}
+
+	statePath := filepath + ".state"
+	state, err := loadShardState(statePath)
+	if err != nil || state == nil || state.URL != url || state.Size != size || state.NumShards != numShards {
+		state = &shardState{URL: url, Size: size, NumShards: numShards, Shards: computeShardRanges(size, numShards)}
+	}
+
+	out, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer out.Close()
+
+	if err := out.Truncate(size); err != nil {
+		return fmt.Errorf("could not allocate file: %w", err)
+	}
+
+	var resumeFrom int64
+	for _, shard := range state.Shards {
+		resumeFrom += shard.Downloaded
+	}
+	tp := newTransferProgress(filename, size, d.reporter, overall, resumeFrom)
+
+	var wg sync.WaitGroup
+	var stateMu sync.Mutex
+	errCh := make(chan error, len(state.Shards))
+
+	for i := range state.Shards {
+		shard := &state.Shards[i]
+		if shard.Downloaded >= shard.End-shard.Start+1 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(shard *fileShard) {
+			defer wg.Done()
+			if err := d.downloadShard(ctx, url, out, shard, &stateMu, state, statePath, extraHeaders, tp); err != nil {
+				errCh <- err
+			}
+		}(shard)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		return err
+	}
+
+	os.Remove(statePath)
+	return nil
+}
+
+// downloadShard fetches a single byte range of url and writes it into out
+// at the shard's offset, checkpointing progress to statePath as it goes.
+func (d *Downloader) downloadShard(ctx context.Context, url string, out *os.File, shard *fileShard, stateMu *sync.Mutex, state *shardState, statePath string, extraHeaders map[string]string, tp *transferProgress) error {
+	offset := shard.Start + shard.Downloaded
+	if offset > shard.End {
+		return nil
+	}
+
+	resp, err := d.doWithRetry(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+
+		if token := os.Getenv(HF_TOKEN_ENV); token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		applyExtraHeaders(req, extraHeaders)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, shard.End))
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("error making range request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		if sentinel := classifyStatusError(resp); sentinel != nil {
+			return fmt.Errorf("range request failed: %w", sentinel)
+		}
+		return fmt.Errorf("unexpected status for range request: %s", resp.Status)
+	}
+
+	lastCheckpoint := time.Now()
+	checkpointInterval := 1 * time.Second
+
+	var body io.Reader = resp.Body
+	if d.bandwidthLimiter != nil {
+		body = d.bandwidthLimiter.wrap(body)
+	}
+
+	buffer := make([]byte, DOWNLOAD_CHUNK_SIZE)
+	for {
+		n, err := body.Read(buffer)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading response: %w", err)
+		}
+
+		if n > 0 {
+			if _, werr := out.WriteAt(buffer[:n], offset); werr != nil {
+				return fmt.Errorf("error writing to file: %w", werr)
+			}
+
+			offset += int64(n)
+			stateMu.Lock()
+			shard.Downloaded += int64(n)
+			stateMu.Unlock()
+			tp.add(int64(n))
+
+			if time.Since(lastCheckpoint) > checkpointInterval {
+				stateMu.Lock()
+				saveShardState(statePath, state)
+				stateMu.Unlock()
+				tp.report()
+				lastCheckpoint = time.Now()
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	stateMu.Lock()
+	saveShardState(statePath, state)
+	stateMu.Unlock()
+	tp.report()
+
+	return nil
+}
+
+// computeShardRanges divides size bytes into numShards contiguous,
+// non-overlapping byte ranges, with any remainder folded into the last shard.
+func computeShardRanges(size int64, numShards int) []fileShard {
+	shards := make([]fileShard, numShards)
+	base := size / int64(numShards)
+
+	var start int64
+	for i := 0; i < numShards; i++ {
+		end := start + base - 1
+		if i == numShards-1 {
+			end = size - 1
+		}
+		shards[i] = fileShard{Start: start, End: end}
+		start = end + 1
+	}
+
+	return shards
+}
+
+func loadShardState(statePath string) (*shardState, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var state shardState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func saveShardState(statePath string, state *shardState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(statePath, data, 0644)
+}
+
 func (d *Downloader) repoFolderName(repoID, repoType string) string {
 	parts := []string{repoType + "s"}
 	parts = append(parts, strings.Split(repoID, "/")...)
@@ -407,20 +944,32 @@ func (d *Downloader) repoFolderName(repoID, repoType string) string {
 
 // ModelInfo represents model repository information from API
 type ModelInfo struct {
-	Siblings []struct {
-		RID  string    `json:"rfilename"`
-		Size int64     `json:"size"`
-		Blob string    `json:"blob_id"`
-		LFS  *struct{} `json:"lfs,omitempty"`
-	} `json:"siblings"`
+	Siblings []ModelSibling `json:"siblings"`
+}
+
+// ModelSibling represents a single file entry in a repository tree listing.
+type ModelSibling struct {
+	RID  string      `json:"rfilename"`
+	Size int64       `json:"size"`
+	Blob string      `json:"blob_id"`
+	LFS  *LfsPointer `json:"lfs,omitempty"`
+}
+
+// LfsPointer identifies the actual Git-LFS object backing a sibling file,
+// as opposed to the (small) pointer file that the tree API otherwise
+// returns in its place.
+type LfsPointer struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
 }
 
 // HfFileMetadata structure similar to Python's HfFileMetadata
 type HfFileMetadata struct {
-	CommitHash string
-	Etag       string
-	Location   string
-	Size       int64
+	CommitHash   string
+	Etag         string
+	Location     string
+	Size         int64
+	AcceptRanges bool
 }
 
 // Utility functions that don't need to be methods
@@ -457,6 +1006,255 @@ func isSymlinkSupported() bool {
 	return err == nil
 }
 
+// applyExtraHeaders sets any headers required by the LFS batch API's
+// download action (e.g. a CDN's own auth token) onto an outgoing request.
+func applyExtraHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// doWithRetry executes a request built by newRequest, retrying on network
+// errors, 5xx responses other than 501, and 429 responses (honoring
+// Retry-After when present) with jittered exponential backoff. newRequest is
+// called again on every attempt so callers can hand back a fresh, unconsumed
+// request body. A successful or non-retryable response (e.g. 404 or 501) is
+// returned as-is for
+// the caller to interpret.
+func (d *Downloader) doWithRetry(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	backoff := d.retryInitialBackoff
+
+	for attempt := 1; attempt <= d.retryMaxAttempts; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := d.client.Do(req)
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			lastErr = err
+		case (resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented) || resp.StatusCode == http.StatusTooManyRequests:
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if resp.StatusCode == http.StatusTooManyRequests {
+				lastErr = fmt.Errorf("server returned status %s: %w", resp.Status, ErrRateLimited)
+			} else {
+				lastErr = fmt.Errorf("server returned status %s", resp.Status)
+			}
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if attempt == d.retryMaxAttempts {
+			break
+		}
+
+		d.logger.Warn("request failed, retrying", "attempt", attempt, "error", lastErr)
+
+		wait := retryAfter
+		if wait <= 0 {
+			wait = jitteredBackoff(backoff)
+		}
+		if !sleepOrDone(ctx, wait) {
+			return nil, ctx.Err()
+		}
+		backoff = nextBackoff(backoff, d.retryMaxBackoff)
+	}
+
+	return nil, lastErr
+}
+
+// classifyStatusError maps well-known HTTP failure statuses to sentinel
+// errors callers can match with errors.Is, returning nil for statuses that
+// don't have a dedicated sentinel.
+func classifyStatusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		return nil
+	}
+}
+
+// nextBackoff doubles cur, capped at max.
+func nextBackoff(cur, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// jitteredBackoff returns a random duration in [d/2, d] to avoid clients
+// retrying in lockstep.
+func jitteredBackoff(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half+1)))
+}
+
+// parseRetryAfter parses a Retry-After header value, which may be either a
+// number of seconds or an HTTP date, returning 0 if absent or unparseable.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// sleepOrDone waits for d, returning false early if ctx is canceled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// errLFSBatchUnavailable signals that the repo's Git-LFS batch endpoint
+// doesn't exist (404) or isn't implemented (501), so callers should fall
+// back to the plain resolve/ URL instead of treating it as a hard failure.
+var errLFSBatchUnavailable = errors.New("LFS batch API unavailable")
+
+// lfsBatchRequest is the body of a Git-LFS Batch API download request.
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/api/batch.md.
+type lfsBatchRequest struct {
+	Operation string           `json:"operation"`
+	Transfers []string         `json:"transfers"`
+	Objects   []lfsBatchObject `json:"objects"`
+}
+
+type lfsBatchObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		Oid     string `json:"oid"`
+		Size    int64  `json:"size"`
+		Actions struct {
+			Download *struct {
+				Href   string            `json:"href"`
+				Header map[string]string `json:"header"`
+			} `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// getLFSObjectURL calls the repo's Git-LFS Batch API to resolve oid to its
+// actual download location (typically a signed CDN URL) plus any headers
+// that location requires. It returns errLFSBatchUnavailable if the repo
+// doesn't support the batch endpoint, so callers can fall back to
+// resolve/<rev>/<path> the way git-lfs itself does.
+func (d *Downloader) getLFSObjectURL(ctx context.Context, repoType RepoType, repoID, oid string, size int64) (string, map[string]string, error) {
+	batchURL := fmt.Sprintf("https://huggingface.co/%s%s.git/info/lfs/objects/batch", repoType.urlPrefix(), repoID)
+
+	reqBody, err := json.Marshal(lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+		Objects:   []lfsBatchObject{{Oid: oid, Size: size}},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error encoding LFS batch request: %w", err)
+	}
+
+	resp, err := d.doWithRetry(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", batchURL, bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("error creating LFS batch request: %w", err)
+		}
+
+		if token := os.Getenv(HF_TOKEN_ENV); token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+		return req, nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error making LFS batch request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		return "", nil, errLFSBatchUnavailable
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		if sentinel := classifyStatusError(resp); sentinel != nil {
+			return "", nil, fmt.Errorf("LFS batch API request failed: %w", sentinel)
+		}
+		return "", nil, fmt.Errorf("LFS batch API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var batchResp lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		return "", nil, fmt.Errorf("error decoding LFS batch response: %w", err)
+	}
+	if len(batchResp.Objects) == 0 {
+		return "", nil, fmt.Errorf("LFS batch API returned no objects for oid %s", oid)
+	}
+
+	object := batchResp.Objects[0]
+	if object.Error != nil {
+		return "", nil, fmt.Errorf("LFS batch API error for oid %s: %s (code %d)", oid, object.Error.Message, object.Error.Code)
+	}
+	if object.Actions.Download == nil {
+		return "", nil, fmt.Errorf("LFS batch API returned no download action for oid %s", oid)
+	}
+
+	return object.Actions.Download.Href, object.Actions.Download.Header, nil
+}
+
+// verifySHA256 streams path through sha256 and compares the digest against
+// expectedOid (optionally prefixed with "sha256:", as Git-LFS oids are).
+func verifySHA256(path, expectedOid string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open file for verification: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return fmt.Errorf("error hashing file: %w", err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	expected := strings.TrimPrefix(expectedOid, "sha256:")
+	if actual != expected {
+		return fmt.Errorf("sha256 mismatch: got %s, expected %s", actual, expected)
+	}
+
+	return nil
+}
+
 func copyFile(src, dst string) error {
 	source, err := os.Open(src)
 	if err != nil {
@@ -473,3 +1271,62 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(destination, source)
 	return err
 }
+
+// bandwidthLimiter is a simple token-bucket limiter shared across every
+// concurrent file and shard download, used to enforce SetBandwidthLimit.
+type bandwidthLimiter struct {
+	bytesPerSecond int64
+
+	mu        sync.Mutex
+	available float64
+	last      time.Time
+}
+
+func newBandwidthLimiter(bytesPerSecond int64) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		bytesPerSecond: bytesPerSecond,
+		available:      float64(bytesPerSecond),
+		last:           time.Now(),
+	}
+}
+
+// wrap returns r wrapped so that reads from it are throttled to the
+// limiter's configured rate.
+func (l *bandwidthLimiter) wrap(r io.Reader) io.Reader {
+	return &rateLimitedReader{r: r, limiter: l}
+}
+
+// take blocks until n bytes' worth of budget is available.
+func (l *bandwidthLimiter) take(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.available += now.Sub(l.last).Seconds() * float64(l.bytesPerSecond)
+	if l.available > float64(l.bytesPerSecond) {
+		l.available = float64(l.bytesPerSecond)
+	}
+	l.last = now
+
+	l.available -= float64(n)
+	if l.available < 0 {
+		wait := time.Duration(-l.available / float64(l.bytesPerSecond) * float64(time.Second))
+		time.Sleep(wait)
+		l.available = 0
+		l.last = time.Now()
+	}
+}
+
+// rateLimitedReader throttles reads from r to its limiter's configured rate.
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter *bandwidthLimiter
+}
+
+func (rl *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := rl.r.Read(p)
+	if n > 0 {
+		rl.limiter.take(n)
+	}
+	return n, err
+}
@@ -1,6 +1,7 @@
 package hfdownloader
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -47,6 +48,215 @@ func TestSetIgnorePatterns(t *testing.T) {
 	}
 }
 
+func TestSetAllowPatterns(t *testing.T) {
+	d := NewDownloader()
+	patterns := []string{"\\.safetensors$", "^config\\.json$"}
+	d.SetAllowPatterns(patterns)
+
+	if len(d.allowPatterns) != len(patterns) {
+		t.Errorf("Expected %d patterns, got %d", len(patterns), len(d.allowPatterns))
+	}
+	for i, pattern := range patterns {
+		if d.allowPatterns[i] != pattern {
+			t.Errorf("Expected pattern %s, got %s", pattern, d.allowPatterns[i])
+		}
+	}
+}
+
+func TestRepoTypePaths(t *testing.T) {
+	cases := []struct {
+		repoType  RepoType
+		apiPath   string
+		urlPrefix string
+	}{
+		{RepoTypeModel, "models", ""},
+		{RepoTypeDataset, "datasets", "datasets/"},
+		{RepoTypeSpace, "spaces", "spaces/"},
+	}
+
+	for _, c := range cases {
+		if got := c.repoType.apiPath(); got != c.apiPath {
+			t.Errorf("%s: expected apiPath %q, got %q", c.repoType, c.apiPath, got)
+		}
+		if got := c.repoType.urlPrefix(); got != c.urlPrefix {
+			t.Errorf("%s: expected urlPrefix %q, got %q", c.repoType, c.urlPrefix, got)
+		}
+	}
+}
+
+func TestRepoFolderName(t *testing.T) {
+	d := NewDownloader()
+
+	cases := []struct {
+		repoType RepoType
+		repoID   string
+		expected string
+	}{
+		{RepoTypeModel, "openai-community/gpt2", "models--openai-community--gpt2"},
+		{RepoTypeDataset, "glue", "datasets--glue"},
+		{RepoTypeSpace, "org/demo", "spaces--org--demo"},
+	}
+
+	for _, c := range cases {
+		if got := d.repoFolderName(c.repoID, string(c.repoType)); got != c.expected {
+			t.Errorf("Expected folder name %q, got %q", c.expected, got)
+		}
+	}
+}
+
+func TestSetMaxConnectionsPerFile(t *testing.T) {
+	d := NewDownloader()
+
+	if d.maxConnectionsPerFile != 1 {
+		t.Errorf("Expected default of 1 connection per file, got %d", d.maxConnectionsPerFile)
+	}
+
+	d.SetMaxConnectionsPerFile(4)
+	if d.maxConnectionsPerFile != 4 {
+		t.Errorf("Expected 4 connections per file, got %d", d.maxConnectionsPerFile)
+	}
+
+	// Values below 1 should be clamped to 1.
+	d.SetMaxConnectionsPerFile(0)
+	if d.maxConnectionsPerFile != 1 {
+		t.Errorf("Expected connections per file clamped to 1, got %d", d.maxConnectionsPerFile)
+	}
+}
+
+func TestSetProgressReporter(t *testing.T) {
+	d := NewDownloader()
+
+	if _, ok := d.reporter.(*SilentProgressReporter); !ok {
+		t.Errorf("Expected default reporter to be SilentProgressReporter, got %T", d.reporter)
+	}
+
+	reporter := NewTerminalProgressReporter()
+	d.SetProgressReporter(reporter)
+	if d.reporter != reporter {
+		t.Errorf("Expected reporter to be set to the provided TerminalProgressReporter")
+	}
+
+	// A nil reporter should fall back to a silent one rather than panicking
+	// on the next report.
+	d.SetProgressReporter(nil)
+	if _, ok := d.reporter.(*SilentProgressReporter); !ok {
+		t.Errorf("Expected nil reporter to fall back to SilentProgressReporter, got %T", d.reporter)
+	}
+}
+
+func TestSetMaxConcurrentFiles(t *testing.T) {
+	d := NewDownloader()
+
+	if d.maxConcurrentFiles < 1 {
+		t.Errorf("Expected a positive default for maxConcurrentFiles, got %d", d.maxConcurrentFiles)
+	}
+
+	d.SetMaxConcurrentFiles(4)
+	if d.maxConcurrentFiles != 4 {
+		t.Errorf("Expected 4 concurrent files, got %d", d.maxConcurrentFiles)
+	}
+
+	// Values below 1 should be clamped to 1.
+	d.SetMaxConcurrentFiles(0)
+	if d.maxConcurrentFiles != 1 {
+		t.Errorf("Expected concurrent files clamped to 1, got %d", d.maxConcurrentFiles)
+	}
+}
+
+func TestSetBandwidthLimit(t *testing.T) {
+	d := NewDownloader()
+
+	if d.bandwidthLimiter != nil {
+		t.Error("Expected no bandwidth limiter by default")
+	}
+
+	d.SetBandwidthLimit(1024)
+	if d.bandwidthLimiter == nil {
+		t.Fatal("Expected a bandwidth limiter to be set")
+	}
+	if d.bandwidthLimiter.bytesPerSecond != 1024 {
+		t.Errorf("Expected 1024 bytes/sec, got %d", d.bandwidthLimiter.bytesPerSecond)
+	}
+
+	// A value <= 0 should remove the cap.
+	d.SetBandwidthLimit(0)
+	if d.bandwidthLimiter != nil {
+		t.Error("Expected bandwidth limiter to be cleared for a non-positive limit")
+	}
+}
+
+func TestSetRetryPolicy(t *testing.T) {
+	d := NewDownloader()
+
+	d.SetRetryPolicy(5, 100*time.Millisecond, 2*time.Second)
+	if d.retryMaxAttempts != 5 {
+		t.Errorf("Expected 5 max attempts, got %d", d.retryMaxAttempts)
+	}
+	if d.retryInitialBackoff != 100*time.Millisecond {
+		t.Errorf("Expected 100ms initial backoff, got %v", d.retryInitialBackoff)
+	}
+	if d.retryMaxBackoff != 2*time.Second {
+		t.Errorf("Expected 2s max backoff, got %v", d.retryMaxBackoff)
+	}
+
+	// A maxAttempts below 1 should be clamped to 1.
+	d.SetRetryPolicy(0, 100*time.Millisecond, 2*time.Second)
+	if d.retryMaxAttempts != 1 {
+		t.Errorf("Expected max attempts clamped to 1, got %d", d.retryMaxAttempts)
+	}
+}
+
+func TestComputeShardRanges(t *testing.T) {
+	shards := computeShardRanges(100, 3)
+	if len(shards) != 3 {
+		t.Fatalf("Expected 3 shards, got %d", len(shards))
+	}
+
+	var total int64
+	for i, shard := range shards {
+		if shard.Start > shard.End {
+			t.Errorf("Shard %d has invalid range: %d-%d", i, shard.Start, shard.End)
+		}
+		total += shard.End - shard.Start + 1
+	}
+
+	if total != 100 {
+		t.Errorf("Expected shards to cover 100 bytes, got %d", total)
+	}
+
+	if shards[len(shards)-1].End != 99 {
+		t.Errorf("Expected last shard to end at byte 99, got %d", shards[len(shards)-1].End)
+	}
+}
+
+func TestVerifySHA256(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "sha256-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString("hello world"); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	// sha256("hello world")
+	const expected = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	if err := verifySHA256(tmpFile.Name(), expected); err != nil {
+		t.Errorf("Expected matching checksum to pass, got error: %v", err)
+	}
+
+	if err := verifySHA256(tmpFile.Name(), "sha256:"+expected); err != nil {
+		t.Errorf("Expected sha256:-prefixed oid to pass, got error: %v", err)
+	}
+
+	if err := verifySHA256(tmpFile.Name(), "0000000000000000000000000000000000000000000000000000000000000000"); err == nil {
+		t.Error("Expected mismatched checksum to return an error, got nil")
+	}
+}
+
 func TestDownload(t *testing.T) {
 	// Create a temporary directory for testing
 	tmpDir, err := os.MkdirTemp("", "hf-test-*")
@@ -62,7 +272,7 @@ func TestDownload(t *testing.T) {
 	modelRepo := "hf-internal-testing/tiny-random-gpt2"
 	revision := "main"
 
-	downloadPath, err := d.Download(modelRepo, revision)
+	downloadPath, err := d.Download(context.Background(), modelRepo, revision)
 	if err != nil {
 		t.Fatalf("Download failed: %v", err)
 	}
@@ -88,7 +298,7 @@ func TestDownload(t *testing.T) {
 
 func TestDownloadWithInvalidRepo(t *testing.T) {
 	d := NewDownloader()
-	_, err := d.Download("invalid/repo/name", "main")
+	_, err := d.Download(context.Background(), "invalid/repo/name", "main")
 	if err == nil {
 		t.Error("Expected error for invalid repository, got nil")
 	}
@@ -111,7 +321,7 @@ func TestDownloadWithToken(t *testing.T) {
 	d.SetCustomPath(tmpDir)
 
 	// Try downloading a private model (this assumes the token has access)
-	_, err = d.Download("your-private-model/test", "main")
+	_, err = d.Download(context.Background(), "your-private-model/test", "main")
 	if err != nil {
 		t.Errorf("Failed to download with token: %v", err)
 	}
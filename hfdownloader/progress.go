@@ -0,0 +1,132 @@
+package hfdownloader
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ProgressReporter receives progress events as a Download runs. Its methods
+// must be safe to call concurrently, since file transfers run on separate
+// worker goroutines.
+type ProgressReporter interface {
+	// FileStarted is called once a file's size is known and its transfer
+	// is about to begin.
+	FileStarted(name string, size int64)
+	// FileProgress is called periodically as a file downloads.
+	FileProgress(name string, written, total int64)
+	// FileCompleted is called once a file has been fully written (or was
+	// already present and didn't need downloading).
+	FileCompleted(name string)
+	// OverallProgress is called periodically with totals across every
+	// file in the current Download call.
+	OverallProgress(bytesDone, bytesTotal int64, filesDone, filesTotal int)
+}
+
+// SilentProgressReporter discards every event. It's the default reporter so
+// that library use doesn't write to stdout unless a caller opts in.
+type SilentProgressReporter struct{}
+
+// NewSilentProgressReporter returns a ProgressReporter that does nothing.
+func NewSilentProgressReporter() *SilentProgressReporter {
+	return &SilentProgressReporter{}
+}
+
+func (*SilentProgressReporter) FileStarted(name string, size int64)            {}
+func (*SilentProgressReporter) FileProgress(name string, written, total int64) {}
+func (*SilentProgressReporter) FileCompleted(name string)                      {}
+func (*SilentProgressReporter) OverallProgress(bytesDone, bytesTotal int64, filesDone, filesTotal int) {
+}
+
+// fileBarState tracks one file's displayed progress line.
+type fileBarState struct {
+	written, total int64
+	done           bool
+}
+
+// TerminalProgressReporter renders one progress line per concurrently
+// downloading file plus an aggregate summary line, redrawing them in place.
+type TerminalProgressReporter struct {
+	mu    sync.Mutex
+	order []string
+	files map[string]fileBarState
+
+	bytesDone, bytesTotal int64
+	filesDone, filesTotal int
+	lastLines             int
+}
+
+// NewTerminalProgressReporter returns a ProgressReporter suited for
+// interactive terminal use.
+func NewTerminalProgressReporter() *TerminalProgressReporter {
+	return &TerminalProgressReporter{files: make(map[string]fileBarState)}
+}
+
+func (r *TerminalProgressReporter) FileStarted(name string, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.files[name] = fileBarState{total: size}
+	r.render()
+}
+
+func (r *TerminalProgressReporter) FileProgress(name string, written, total int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.files[name] = fileBarState{written: written, total: total}
+	r.render()
+}
+
+func (r *TerminalProgressReporter) FileCompleted(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state := r.files[name]
+	state.done = true
+	if state.total > 0 {
+		state.written = state.total
+	}
+	r.files[name] = state
+	r.render()
+}
+
+func (r *TerminalProgressReporter) OverallProgress(bytesDone, bytesTotal int64, filesDone, filesTotal int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytesDone, r.bytesTotal, r.filesDone, r.filesTotal = bytesDone, bytesTotal, filesDone, filesTotal
+	r.render()
+}
+
+// render redraws every in-progress file's line plus the aggregate line,
+// overwriting whatever it printed last time. Callers must hold r.mu.
+func (r *TerminalProgressReporter) render() {
+	lines := make([]string, 0, len(r.order)+1)
+	for _, name := range r.order {
+		state := r.files[name]
+		if state.done {
+			continue
+		}
+		if state.total > 0 {
+			lines = append(lines, fmt.Sprintf("  %s: %.1f%% (%d/%d bytes)",
+				name, float64(state.written)/float64(state.total)*100, state.written, state.total))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: %d bytes", name, state.written))
+		}
+	}
+	if r.bytesTotal > 0 {
+		lines = append(lines, fmt.Sprintf("Overall: %.1f%% (%d/%d files, %d/%d bytes)",
+			float64(r.bytesDone)/float64(r.bytesTotal)*100, r.filesDone, r.filesTotal, r.bytesDone, r.bytesTotal))
+	}
+
+	if r.lastLines > 0 {
+		fmt.Printf("\033[%dA", r.lastLines)
+	}
+	for _, line := range lines {
+		fmt.Printf("\033[2K%s\n", line)
+	}
+	r.lastLines = len(lines)
+}
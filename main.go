@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -27,6 +28,7 @@ func main() {
 
 	// Create downloader instance
 	downloader := hfdownloader.NewDownloader()
+	downloader.SetProgressReporter(hfdownloader.NewTerminalProgressReporter())
 
 	// Set custom path if provided
 	if customPath != "" {
@@ -39,7 +41,7 @@ func main() {
 		fmt.Printf("Download location: %s\n", customPath)
 	}
 
-	downloadPath, err := downloader.Download(modelRepo, revision)
+	downloadPath, err := downloader.Download(context.Background(), modelRepo, revision)
 	if err != nil {
 		fmt.Printf("Error downloading model: %v\n", err)
 		os.Exit(1)